@@ -0,0 +1,96 @@
+// Package errors wraps the standard library's errors package with single-frame stack capture,
+// so operator logs can show where an error crossed a package boundary without the noise of a
+// full stack dump.
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// New is a re-export of the standard library's errors.New, for defining sentinel errors.
+var New = errors.New
+
+// Is is a re-export of the standard library's errors.Is.
+var Is = errors.Is
+
+// As is a re-export of the standard library's errors.As.
+var As = errors.As
+
+// frame is the file:line an error was wrapped or crossed into our code at.
+type frame struct {
+	file string
+	line int
+}
+
+func caller(skip int) frame {
+	_, file, line, ok := runtime.Caller(skip)
+	if !ok {
+		return frame{file: "unknown", line: 0}
+	}
+	return frame{file: file, line: line}
+}
+
+// withFrame records the frame an error was wrapped at alongside the error itself.
+type withFrame struct {
+	err   error
+	frame frame
+}
+
+func (w *withFrame) Error() string { return w.err.Error() }
+func (w *withFrame) Unwrap() error { return w.err }
+
+// Wrap annotates err with msg and the file:line Wrap was called from. It returns nil if err is
+// nil, so callers can write `return errors.Wrap(err, "...")` unconditionally.
+func Wrap(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return &withFrame{err: fmt.Errorf("%s: %w", msg, err), frame: caller(2)}
+}
+
+// Wrapf is like Wrap but formats msg with args.
+func Wrapf(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return &withFrame{err: fmt.Errorf(format+": %w", append(args, err)...), frame: caller(2)}
+}
+
+// WithStack annotates an error from an external package (e.g. gorm) with the file:line it
+// crossed into our code, without changing its message.
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &withFrame{err: err, frame: caller(2)}
+}
+
+// Errorf builds a new error (no underlying error to wrap) formatted with format/args, capturing
+// the file:line Errorf was called from. Use this instead of fmt.Errorf for orm-local errors that
+// don't wrap an existing error, so they still show up in Field's frame chain.
+func Errorf(format string, args ...interface{}) error {
+	return &withFrame{err: fmt.Errorf(format, args...), frame: caller(2)}
+}
+
+// Field renders err for a structured logger (e.g. zerolog/go-ethereum's log), walking the
+// wrapped chain and printing one file:line per Wrap/WithStack call site instead of a full
+// stack dump.
+func Field(err error) string {
+	if err == nil {
+		return ""
+	}
+	var frames []string
+	for cur := err; cur != nil; cur = errors.Unwrap(cur) {
+		if wf, ok := cur.(*withFrame); ok {
+			frames = append(frames, fmt.Sprintf("%s:%d", filepath.Base(wf.frame.file), wf.frame.line))
+		}
+	}
+	if len(frames) == 0 {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s (%s)", err.Error(), strings.Join(frames, " <- "))
+}
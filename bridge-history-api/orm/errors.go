@@ -0,0 +1,15 @@
+package orm
+
+import "scroll-tech/bridge-history-api/internal/errors"
+
+// Sentinel errors returned by this package. Callers should check against these with errors.Is
+// rather than comparing to gorm.ErrRecordNotFound directly, which loses the distinction between
+// "no row" and "empty result" once a method wraps or aggregates it.
+var (
+	// ErrRelayedMsgNotFound is returned when a relayed msg lookup finds no matching row.
+	ErrRelayedMsgNotFound = errors.New("relayed msg not found")
+	// ErrDuplicateMsgHash is returned when an insert collides with an existing msg_hash.
+	ErrDuplicateMsgHash = errors.New("duplicate msg hash")
+	// ErrTxConflict is returned when a transaction aborts due to a serialization conflict.
+	ErrTxConflict = errors.New("transaction conflict")
+)
@@ -0,0 +1,147 @@
+package orm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"scroll-tech/bridge-history-api/internal/errors"
+)
+
+// defaultFindRelayedMsgsLimit is used when FindRelayedMsgsOpts.Limit is unset.
+const defaultFindRelayedMsgsLimit = 100
+
+// OrderDirection selects which way FindRelayedMsgs sorts results by height.
+type OrderDirection int
+
+const (
+	// OrderByHeightAsc sorts ascending by height (oldest first).
+	OrderByHeightAsc OrderDirection = iota
+	// OrderByHeightDesc sorts descending by height (newest first).
+	OrderByHeightDesc
+)
+
+// HeightRange restricts FindRelayedMsgs to rows with From <= height <= To.
+type HeightRange struct {
+	From uint64
+	To   uint64
+}
+
+// LayerEither is the zero value of Layer. Passed as FindRelayedMsgsOpts.Layer, it matches rows
+// relayed on either layer; any other unrecognized Layer value is rejected.
+const LayerEither Layer = 0
+
+// FindRelayedMsgsOpts configures FindRelayedMsgs. The zero value matches every row, ordered by
+// height ascending, and returns up to defaultFindRelayedMsgsLimit rows.
+type FindRelayedMsgsOpts struct {
+	HeightRange *HeightRange
+	// Layer restricts results to rows relayed on that layer. LayerEither matches either layer.
+	Layer         Layer
+	HasLayer1Hash *bool
+	HasLayer2Hash *bool
+	MsgHashPrefix string
+	// AfterID is the opaque cursor returned as nextCursor by a previous call; leave empty to
+	// fetch the first page.
+	AfterID string
+	Limit   int
+	OrderBy OrderDirection
+}
+
+// relayedMsgCursor is the decoded form of the opaque cursor FindRelayedMsgs hands out.
+type relayedMsgCursor struct {
+	ID     uint64 `json:"id"`
+	Height uint64 `json:"height"`
+}
+
+func encodeRelayedMsgCursor(id, height uint64) string {
+	raw, _ := json.Marshal(relayedMsgCursor{ID: id, Height: height})
+	return base64.URLEncoding.EncodeToString(raw)
+}
+
+func decodeRelayedMsgCursor(s string) (relayedMsgCursor, error) {
+	var c relayedMsgCursor
+	raw, err := base64.URLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.Wrapf(err, "orm: invalid cursor %q", s)
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, errors.Wrapf(err, "orm: invalid cursor %q", s)
+	}
+	return c, nil
+}
+
+// layerFilterClause returns the WHERE clause restricting results to layer, or "" for LayerEither.
+func layerFilterClause(layer Layer) (string, error) {
+	switch layer {
+	case LayerEither:
+		return "", nil
+	case LayerL1:
+		return "layer1_hash != ''", nil
+	case LayerL2:
+		return "layer2_hash != ''", nil
+	default:
+		return "", errors.Errorf("orm: unknown layer %d", layer)
+	}
+}
+
+// FindRelayedMsgs returns relayed messages matching opts together with an opaque cursor for the
+// next page. Pass the returned cursor back as opts.AfterID to keep paging stably even as new
+// rows are inserted; an empty nextCursor means there are no more rows.
+func (r *RelayedMsg) FindRelayedMsgs(ctx context.Context, opts FindRelayedMsgsOpts) ([]*RelayedMsg, string, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultFindRelayedMsgsLimit
+	}
+
+	query := r.db.WithContext(ctx).Model(&RelayedMsg{})
+
+	if opts.HeightRange != nil {
+		query = query.Where("height >= ? AND height <= ?", opts.HeightRange.From, opts.HeightRange.To)
+	}
+	layerWhere, err := layerFilterClause(opts.Layer)
+	if err != nil {
+		return nil, "", err
+	}
+	if layerWhere != "" {
+		query = query.Where(layerWhere)
+	}
+	if opts.HasLayer1Hash != nil {
+		query = query.Where("(layer1_hash != '') = ?", *opts.HasLayer1Hash)
+	}
+	if opts.HasLayer2Hash != nil {
+		query = query.Where("(layer2_hash != '') = ?", *opts.HasLayer2Hash)
+	}
+	if opts.MsgHashPrefix != "" {
+		query = query.Where("msg_hash LIKE ?", opts.MsgHashPrefix+"%")
+	}
+
+	desc := opts.OrderBy == OrderByHeightDesc
+	if opts.AfterID != "" {
+		after, err := decodeRelayedMsgCursor(opts.AfterID)
+		if err != nil {
+			return nil, "", err
+		}
+		if desc {
+			query = query.Where("(height, id) < (?, ?)", after.Height, after.ID)
+		} else {
+			query = query.Where("(height, id) > (?, ?)", after.Height, after.ID)
+		}
+	}
+
+	order := "height ASC, id ASC"
+	if desc {
+		order = "height DESC, id DESC"
+	}
+
+	var results []*RelayedMsg
+	if err := query.Order(order).Limit(limit).Find(&results).Error; err != nil {
+		return nil, "", errors.Wrap(err, "RelayedMsg.FindRelayedMsgs error")
+	}
+
+	var nextCursor string
+	if len(results) == limit {
+		last := results[len(results)-1]
+		nextCursor = encodeRelayedMsgCursor(last.ID, last.Height)
+	}
+	return results, nextCursor, nil
+}
@@ -0,0 +1,65 @@
+package orm
+
+import "testing"
+
+func TestRelayedMsgCursorRoundTrip(t *testing.T) {
+	cases := []relayedMsgCursor{
+		{ID: 0, Height: 0},
+		{ID: 42, Height: 1000},
+		{ID: 18446744073709551615, Height: 18446744073709551615}, // max uint64
+	}
+	for _, c := range cases {
+		encoded := encodeRelayedMsgCursor(c.ID, c.Height)
+		if encoded == "" {
+			t.Fatalf("encodeRelayedMsgCursor(%d, %d) returned empty string", c.ID, c.Height)
+		}
+		decoded, err := decodeRelayedMsgCursor(encoded)
+		if err != nil {
+			t.Fatalf("decodeRelayedMsgCursor(%q) error: %v", encoded, err)
+		}
+		if decoded != c {
+			t.Fatalf("round trip mismatch: got %+v, want %+v", decoded, c)
+		}
+	}
+}
+
+func TestLayerFilterClause(t *testing.T) {
+	cases := []struct {
+		layer   Layer
+		want    string
+		wantErr bool
+	}{
+		{LayerEither, "", false},
+		{LayerL1, "layer1_hash != ''", false},
+		{LayerL2, "layer2_hash != ''", false},
+		{Layer(99), "", true},
+	}
+	for _, c := range cases {
+		got, err := layerFilterClause(c.layer)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("layer %d: expected error, got nil", c.layer)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("layer %d: unexpected error: %v", c.layer, err)
+		}
+		if got != c.want {
+			t.Errorf("layer %d: got %q, want %q", c.layer, got, c.want)
+		}
+	}
+}
+
+func TestDecodeRelayedMsgCursor_Invalid(t *testing.T) {
+	cases := []string{
+		"", // not a real cursor; callers only decode when AfterID != ""
+		"not-valid-base64!!",
+		"bm90LWpzb24=", // valid base64, but not JSON
+	}
+	for _, s := range cases {
+		if _, err := decodeRelayedMsgCursor(s); err == nil {
+			t.Errorf("decodeRelayedMsgCursor(%q): expected error, got nil", s)
+		}
+	}
+}
@@ -2,11 +2,13 @@ package orm
 
 import (
 	"context"
-	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
 	"gorm.io/gorm"
+
+	"scroll-tech/bridge-history-api/internal/errors"
 )
 
 // RelayedMsg is the struct for relayed_msg table
@@ -16,6 +18,7 @@ type RelayedMsg struct {
 	ID         uint64         `json:"id" gorm:"column:id"`
 	MsgHash    string         `json:"msg_hash" gorm:"column:msg_hash"`
 	Height     uint64         `json:"height" gorm:"column:height"`
+	BlockHash  string         `json:"block_hash" gorm:"column:block_hash;default:''"`
 	Layer1Hash string         `json:"layer1_hash" gorm:"column:layer1_hash;default:''"`
 	Layer2Hash string         `json:"layer2_hash" gorm:"column:layer2_hash;default:''"`
 	CreatedAt  *time.Time     `json:"created_at" gorm:"column:created_at"`
@@ -23,6 +26,27 @@ type RelayedMsg struct {
 	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"column:deleted_at;default:NULL"`
 }
 
+// Layer identifies which side of the bridge a relayed message or indexed block belongs to.
+type Layer int
+
+const (
+	// LayerL1 identifies layer 1 (Ethereum).
+	LayerL1 Layer = iota + 1
+	// LayerL2 identifies layer 2 (Scroll).
+	LayerL2
+)
+
+func layerHashColumn(layer Layer) (string, error) {
+	switch layer {
+	case LayerL1:
+		return "layer1_hash", nil
+	case LayerL2:
+		return "layer2_hash", nil
+	default:
+		return "", errors.Errorf("orm: unknown layer %d", layer)
+	}
+}
+
 // NewRelayedMsg create an NewRelayedMsg instance
 func NewRelayedMsg(db *gorm.DB) *RelayedMsg {
 	return &RelayedMsg{db: db}
@@ -33,7 +57,9 @@ func (*RelayedMsg) TableName() string {
 	return "relayed_msg"
 }
 
-// GetRelayedMsgByHash get relayed msg by hash
+// GetRelayedMsgByHash get relayed msg by hash. Returns ErrRelayedMsgNotFound (check with
+// errors.Is) instead of a nil error when no row matches; existing callers that treated a nil
+// result as "not found" need updating to check the error instead.
 func (r *RelayedMsg) GetRelayedMsgByHash(ctx context.Context, msgHash string) (*RelayedMsg, error) {
 	var result RelayedMsg
 	err := r.db.WithContext(ctx).Model(&RelayedMsg{}).
@@ -42,9 +68,9 @@ func (r *RelayedMsg) GetRelayedMsgByHash(ctx context.Context, msgHash string) (*
 		Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, nil
+			return nil, ErrRelayedMsgNotFound
 		}
-		return nil, fmt.Errorf("RelayedMsg.GetRelayedMsgByHash error: %w", err)
+		return nil, errors.Wrap(err, "RelayedMsg.GetRelayedMsgByHash error")
 	}
 	return &result, nil
 }
@@ -57,7 +83,7 @@ func (r *RelayedMsg) GetRelayedMsgsByHashes(ctx context.Context, msgHashes []str
 		Find(&results).
 		Error
 	if err != nil {
-		return nil, fmt.Errorf("RelayedMsg.GetRelayedMsgsByHashes error: %w", err)
+		return nil, errors.Wrap(err, "RelayedMsg.GetRelayedMsgsByHashes error")
 	}
 	return results, nil
 }
@@ -75,7 +101,7 @@ func (r *RelayedMsg) GetLatestRelayedHeightOnL1(ctx context.Context) (uint64, er
 		if err == gorm.ErrRecordNotFound {
 			return 0, nil
 		}
-		return 0, fmt.Errorf("RelayedMsg.GetLatestRelayedHeightOnL1 error: %w", err)
+		return 0, errors.Wrap(err, "RelayedMsg.GetLatestRelayedHeightOnL1 error")
 	}
 	return result.Height, err
 }
@@ -93,11 +119,56 @@ func (r *RelayedMsg) GetLatestRelayedHeightOnL2(ctx context.Context) (uint64, er
 		if err == gorm.ErrRecordNotFound {
 			return 0, nil
 		}
-		return 0, fmt.Errorf("RelayedMsg.GetLatestRelayedHeightOnL2 error: %w", err)
+		return 0, errors.Wrap(err, "RelayedMsg.GetLatestRelayedHeightOnL2 error")
 	}
 	return result.Height, nil
 }
 
+// FindLatestIndexedBlock returns the height and block hash of the most recently indexed block on
+// the given layer, or (0, "", nil) if nothing has been indexed yet.
+func (r *RelayedMsg) FindLatestIndexedBlock(ctx context.Context, layer Layer) (uint64, string, error) {
+	column, err := layerHashColumn(layer)
+	if err != nil {
+		return 0, "", err
+	}
+	var result RelayedMsg
+	err = r.db.WithContext(ctx).Model(&RelayedMsg{}).
+		Select("height, block_hash").
+		Where(column+" != ''").
+		Order("height DESC").
+		First(&result).
+		Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return 0, "", nil
+		}
+		return 0, "", errors.Wrap(err, "RelayedMsg.FindLatestIndexedBlock error")
+	}
+	return result.Height, result.BlockHash, nil
+}
+
+// hashAtHeight returns the block hash indexed for layer at height, or "" if no row was indexed
+// for that layer at that height.
+func (r *RelayedMsg) hashAtHeight(ctx context.Context, layer Layer, height uint64) (string, error) {
+	column, err := layerHashColumn(layer)
+	if err != nil {
+		return "", err
+	}
+	var result RelayedMsg
+	err = r.db.WithContext(ctx).Model(&RelayedMsg{}).
+		Select("block_hash").
+		Where("height = ? AND "+column+" != ''", height).
+		First(&result).
+		Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", nil
+		}
+		return "", errors.Wrap(err, "RelayedMsg.hashAtHeight error")
+	}
+	return result.BlockHash, nil
+}
+
 // InsertRelayedMsg batch insert relayed msg into db and return the transaction
 func (r *RelayedMsg) InsertRelayedMsg(ctx context.Context, messages []*RelayedMsg, dbTx ...*gorm.DB) error {
 	if len(messages) == 0 {
@@ -107,7 +178,7 @@ func (r *RelayedMsg) InsertRelayedMsg(ctx context.Context, messages []*RelayedMs
 	if len(dbTx) > 0 && dbTx[0] != nil {
 		db = dbTx[0]
 	}
-	db.WithContext(ctx)
+	db = db.WithContext(ctx)
 	err := db.Model(&RelayedMsg{}).Create(&messages).Error
 	if err != nil {
 		l2hashes := make([]string, 0, len(messages))
@@ -118,38 +189,51 @@ func (r *RelayedMsg) InsertRelayedMsg(ctx context.Context, messages []*RelayedMs
 			l1hashes = append(l1hashes, msg.Layer1Hash)
 			heights = append(heights, msg.Height)
 		}
-		log.Error("failed to insert l2 sent messages", "l2hashes", l2hashes, "l1hashes", l1hashes, "heights", heights, "err", err)
-		return fmt.Errorf("RelayedMsg.InsertRelayedMsg error: %w", err)
+		log.Error("failed to insert l2 sent messages", "l2hashes", l2hashes, "l1hashes", l1hashes, "heights", heights, "err", errors.Field(errors.WithStack(err)))
+		if isDuplicateKeyError(err) {
+			return errors.Wrap(ErrDuplicateMsgHash, "RelayedMsg.InsertRelayedMsg error")
+		}
+		return errors.Wrap(err, "RelayedMsg.InsertRelayedMsg error")
 	}
 	return nil
 }
 
+// isDuplicateKeyError reports whether err is a unique-constraint violation from the database driver.
+func isDuplicateKeyError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "duplicate key")
+}
+
+// isSerializationConflictError reports whether err is a Postgres serialization failure (SQLSTATE
+// 40001).
+func isSerializationConflictError(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "SQLSTATE 40001") || strings.Contains(err.Error(), "could not serialize access"))
+}
+
 // DeleteL1RelayedHashAfterHeight delete l1 relayed hash after height
 func (r *RelayedMsg) DeleteL1RelayedHashAfterHeight(ctx context.Context, height uint64, dbTx ...*gorm.DB) error {
-	db := r.db
-	if len(dbTx) > 0 && dbTx[0] != nil {
-		db = dbTx[0]
-	}
-	db.WithContext(ctx)
-	err := db.Model(&RelayedMsg{}).
-		Delete("height > ? AND layer1_hash != ''", height).Error
-	if err != nil {
-		return fmt.Errorf("RelayedMsg.DeleteL1RelayedHashAfterHeight error: %w", err)
-	}
-	return nil
+	return r.DeleteAllRelayedAfterBlock(ctx, LayerL1, height, dbTx...)
 }
 
 // DeleteL2RelayedHashAfterHeight delete l2 relayed hash after heights
 func (r *RelayedMsg) DeleteL2RelayedHashAfterHeight(ctx context.Context, height uint64, dbTx ...*gorm.DB) error {
+	return r.DeleteAllRelayedAfterBlock(ctx, LayerL2, height, dbTx...)
+}
+
+// DeleteAllRelayedAfterBlock deletes every relayed-msg hash recorded for layer after height.
+func (r *RelayedMsg) DeleteAllRelayedAfterBlock(ctx context.Context, layer Layer, height uint64, dbTx ...*gorm.DB) error {
+	column, err := layerHashColumn(layer)
+	if err != nil {
+		return err
+	}
 	db := r.db
 	if len(dbTx) > 0 && dbTx[0] != nil {
 		db = dbTx[0]
 	}
-	db.WithContext(ctx)
-	err := db.Model(&RelayedMsg{}).
-		Delete("height > ? AND layer2_hash != ''", height).Error
+	db = db.WithContext(ctx)
+	err = db.Model(&RelayedMsg{}).
+		Delete("height > ? AND "+column+" != ''", height).Error
 	if err != nil {
-		return fmt.Errorf("RelayedMsg.DeleteL2RelayedHashAfterHeight error: %w", err)
+		return errors.Wrap(err, "RelayedMsg.DeleteAllRelayedAfterBlock error")
 	}
 	return nil
 }
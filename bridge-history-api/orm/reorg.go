@@ -0,0 +1,141 @@
+package orm
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"scroll-tech/bridge-history-api/internal/errors"
+)
+
+// IndexingMode controls where SetInitialIndexingHeight resumes scanning from.
+type IndexingMode int
+
+const (
+	// Sync resumes indexing from the highest block height already recorded in the database.
+	Sync IndexingMode = iota
+	// Resync restarts indexing from genesis, discarding any progress already recorded.
+	Resync
+	// Latest skips any backlog and starts indexing from the current chain tip.
+	Latest
+)
+
+// ChainClient is the minimal read interface ReorgDetector needs from the L1/L2 client to detect
+// reorgs and locate the chain tip.
+type ChainClient interface {
+	// HeaderHashByNumber returns the canonical block hash at number according to the chain.
+	HeaderHashByNumber(ctx context.Context, number uint64) (string, error)
+	// BlockNumber returns the chain's current tip height.
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// indexedHashSource is the subset of *RelayedMsg's behavior ReorgDetector needs to look up and
+// roll back previously indexed (height, hash) pairs.
+type indexedHashSource interface {
+	hashAtHeight(ctx context.Context, layer Layer, height uint64) (string, error)
+	FindLatestIndexedBlock(ctx context.Context, layer Layer) (uint64, string, error)
+	DeleteAllRelayedAfterBlock(ctx context.Context, layer Layer, height uint64, dbTx ...*gorm.DB) error
+}
+
+// ReorgDetector detects L1/L2 reorgs by comparing an incoming block's parent hash against the
+// (height, hash) pair already indexed in relayed_msg. On a mismatch it walks backwards,
+// comparing indexed hashes against the chain, until it finds the last common ancestor (or the
+// first height with no indexed hash), then
+// rolls back everything indexed after it inside a single transaction.
+type ReorgDetector struct {
+	db     *gorm.DB
+	msg    indexedHashSource
+	layer  Layer
+	client ChainClient
+}
+
+// NewReorgDetector creates a ReorgDetector for the given layer.
+func NewReorgDetector(db *gorm.DB, layer Layer, client ChainClient) *ReorgDetector {
+	return &ReorgDetector{db: db, msg: NewRelayedMsg(db), layer: layer, client: client}
+}
+
+// findCommonAncestorHeight walks backwards from searchFrom (exclusive), comparing the database's
+// indexed hash at each height against the chain's hash, stopping at the first height where they
+// agree, or at the first height with no indexed hash if the database runs out of indexed history
+// first (0 if the walk reaches genesis).
+func findCommonAncestorHeight(ctx context.Context, layer Layer, searchFrom uint64, msg indexedHashSource, client ChainClient) (uint64, error) {
+	ancestor := searchFrom
+	for ancestor > 0 {
+		ancestor--
+		dbHash, err := msg.hashAtHeight(ctx, layer, ancestor)
+		if err != nil {
+			return 0, err
+		}
+		if dbHash == "" {
+			break
+		}
+		chainHash, err := client.HeaderHashByNumber(ctx, ancestor)
+		if err != nil {
+			return 0, err
+		}
+		if chainHash == dbHash {
+			break
+		}
+	}
+	return ancestor, nil
+}
+
+// DetectAndRollback checks whether parentHash (the parent hash reported by the block at height)
+// matches what was indexed at height-1. If it does, or nothing was indexed at height-1, it
+// returns height unchanged. If it doesn't, it walks backwards through previously indexed heights,
+// asking the chain for the hash at each one, until it finds a height where the chain and the
+// database agree (the common ancestor) or a height with no indexed hash, deletes everything indexed after that ancestor in a single transaction, and returns the
+// height indexing should resume from.
+func (d *ReorgDetector) DetectAndRollback(ctx context.Context, height uint64, parentHash string) (uint64, error) {
+	if height == 0 {
+		return 0, nil
+	}
+
+	storedParentHash, err := d.msg.hashAtHeight(ctx, d.layer, height-1)
+	if err != nil {
+		return 0, errors.Wrap(err, "ReorgDetector.DetectAndRollback error")
+	}
+	if storedParentHash == "" || storedParentHash == parentHash {
+		return height, nil
+	}
+
+	ancestor, err := findCommonAncestorHeight(ctx, d.layer, height-1, d.msg, d.client)
+	if err != nil {
+		return 0, errors.Wrap(err, "ReorgDetector.DetectAndRollback error")
+	}
+
+	err = d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return d.msg.DeleteAllRelayedAfterBlock(ctx, d.layer, ancestor, tx)
+	})
+	if err != nil {
+		if isSerializationConflictError(err) {
+			return 0, errors.Wrap(ErrTxConflict, "ReorgDetector.DetectAndRollback error")
+		}
+		return 0, errors.Wrap(err, "ReorgDetector.DetectAndRollback error")
+	}
+	return ancestor + 1, nil
+}
+
+// SetInitialIndexingHeight returns the height the indexer should start scanning from for mode:
+// Sync resumes from the highest height already indexed, Resync starts over from genesis, and
+// Latest jumps straight to the chain tip, skipping any backlog.
+func (d *ReorgDetector) SetInitialIndexingHeight(ctx context.Context, mode IndexingMode) (uint64, error) {
+	switch mode {
+	case Resync:
+		return 0, nil
+	case Latest:
+		tip, err := d.client.BlockNumber(ctx)
+		if err != nil {
+			return 0, errors.Wrap(err, "ReorgDetector.SetInitialIndexingHeight error")
+		}
+		return tip, nil
+	case Sync:
+		fallthrough
+	default:
+		height, _, err := d.msg.FindLatestIndexedBlock(ctx, d.layer)
+		if err != nil {
+			return 0, errors.Wrap(err, "ReorgDetector.SetInitialIndexingHeight error")
+		}
+		return height, nil
+	}
+}
@@ -0,0 +1,121 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// fakeHashSource is an in-memory indexedHashSource used to unit-test the reorg logic without a
+// real database. heights maps height -> indexed block hash for a single layer.
+type fakeHashSource struct {
+	heights map[uint64]string
+	deleted []uint64 // heights passed to DeleteAllRelayedAfterBlock, for assertions
+}
+
+func (f *fakeHashSource) hashAtHeight(_ context.Context, _ Layer, height uint64) (string, error) {
+	return f.heights[height], nil
+}
+
+func (f *fakeHashSource) FindLatestIndexedBlock(_ context.Context, _ Layer) (uint64, string, error) {
+	var best uint64
+	hash, ok := "", false
+	for h, hh := range f.heights {
+		if hh != "" && (!ok || h > best) {
+			best, hash, ok = h, hh, true
+		}
+	}
+	return best, hash, nil
+}
+
+func (f *fakeHashSource) DeleteAllRelayedAfterBlock(_ context.Context, _ Layer, height uint64, _ ...*gorm.DB) error {
+	f.deleted = append(f.deleted, height)
+	return nil
+}
+
+// fakeChainClient is an in-memory ChainClient used alongside fakeHashSource.
+type fakeChainClient struct {
+	headers map[uint64]string
+	tip     uint64
+}
+
+func (f *fakeChainClient) HeaderHashByNumber(_ context.Context, number uint64) (string, error) {
+	return f.headers[number], nil
+}
+
+func (f *fakeChainClient) BlockNumber(_ context.Context) (uint64, error) {
+	return f.tip, nil
+}
+
+func TestFindCommonAncestorHeight_NoReorg(t *testing.T) {
+	msg := &fakeHashSource{heights: map[uint64]string{8: "h8", 9: "h9"}}
+	client := &fakeChainClient{headers: map[uint64]string{9: "h9"}}
+
+	ancestor, err := findCommonAncestorHeight(context.Background(), LayerL1, 10, msg, client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ancestor != 9 {
+		t.Fatalf("expected ancestor 9, got %d", ancestor)
+	}
+}
+
+func TestFindCommonAncestorHeight_WalksBackThroughReorg(t *testing.T) {
+	// The chain reorged everything above height 6: the db still has the old (now orphaned)
+	// hashes for 7..9, but the chain's hash at 6 matches what's stored in the db.
+	msg := &fakeHashSource{heights: map[uint64]string{
+		6: "h6", 7: "old-h7", 8: "old-h8", 9: "old-h9",
+	}}
+	client := &fakeChainClient{headers: map[uint64]string{
+		6: "h6", 7: "new-h7", 8: "new-h8",
+	}}
+
+	ancestor, err := findCommonAncestorHeight(context.Background(), LayerL1, 9, msg, client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ancestor != 6 {
+		t.Fatalf("expected common ancestor 6, got %d", ancestor)
+	}
+}
+
+func TestFindCommonAncestorHeight_StopsAtIndexingStart(t *testing.T) {
+	// The reorg goes deeper than our retained history: heights 3 and 4 are both orphaned and
+	// disagree with the chain, but nothing is indexed below height 3. The walk should stop at
+	// height 2 (the first height with no stored hash) instead of underflowing past it.
+	msg := &fakeHashSource{heights: map[uint64]string{3: "old-h3", 4: "old-h4"}}
+	client := &fakeChainClient{headers: map[uint64]string{3: "new-h3"}}
+
+	ancestor, err := findCommonAncestorHeight(context.Background(), LayerL1, 4, msg, client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ancestor != 2 {
+		t.Fatalf("expected ancestor 2, got %d", ancestor)
+	}
+}
+
+func TestReorgDetector_SetInitialIndexingHeight(t *testing.T) {
+	msg := &fakeHashSource{heights: map[uint64]string{5: "h5", 9: "h9"}}
+	client := &fakeChainClient{tip: 42}
+	d := &ReorgDetector{msg: msg, layer: LayerL1, client: client}
+
+	tests := []struct {
+		mode IndexingMode
+		want uint64
+	}{
+		{Sync, 9},
+		{Resync, 0},
+		{Latest, 42},
+	}
+	for _, tt := range tests {
+		got, err := d.SetInitialIndexingHeight(context.Background(), tt.mode)
+		if err != nil {
+			t.Fatalf("mode %v: unexpected error: %v", tt.mode, err)
+		}
+		if got != tt.want {
+			t.Errorf("mode %v: got height %d, want %d", tt.mode, got, tt.want)
+		}
+	}
+}
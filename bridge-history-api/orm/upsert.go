@@ -0,0 +1,131 @@
+package orm
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"scroll-tech/bridge-history-api/internal/errors"
+)
+
+// defaultUpsertChunkSize is the rows per statement used when InsertOrUpdateOpts.ChunkSize is unset.
+const defaultUpsertChunkSize = 500
+
+// ConflictStrategy controls how InsertOrUpdateRelayedMsg resolves a msg_hash collision.
+type ConflictStrategy int
+
+const (
+	// ConflictIgnore skips rows whose msg_hash already exists, leaving the stored row untouched.
+	ConflictIgnore ConflictStrategy = iota
+	// ConflictUpdateHashes fills in whichever of layer1_hash/layer2_hash is empty on the stored row.
+	ConflictUpdateHashes
+	// ConflictReplace overwrites height, block_hash and both layer hashes on the stored row with the
+	// incoming values.
+	ConflictReplace
+)
+
+// InsertOrUpdateOpts configures InsertOrUpdateRelayedMsg.
+type InsertOrUpdateOpts struct {
+	Strategy  ConflictStrategy
+	ChunkSize int // defaults to defaultUpsertChunkSize when <= 0
+}
+
+// BatchInsertError reports the msg hashes whose chunk failed to upsert.
+type BatchInsertError struct {
+	MsgHashes []string
+	Err       error
+
+	msg string
+}
+
+// newBatchInsertError builds the formatted message once, at construction time.
+func newBatchInsertError(msgHashes []string, err error) *BatchInsertError {
+	return &BatchInsertError{
+		MsgHashes: msgHashes,
+		Err:       err,
+		msg:       errors.Field(errors.Wrapf(err, "batch upsert failed for %d msg hash(es)", len(msgHashes))),
+	}
+}
+
+// Error implements the error interface.
+func (e *BatchInsertError) Error() string {
+	return e.msg
+}
+
+// Unwrap allows errors.Is/errors.As to see through to the underlying error.
+func (e *BatchInsertError) Unwrap() error {
+	return e.Err
+}
+
+// buildOnConflictClause translates a ConflictStrategy into the gorm ON CONFLICT clause that
+// implements it.
+func buildOnConflictClause(strategy ConflictStrategy) clause.OnConflict {
+	onConflict := clause.OnConflict{Columns: []clause.Column{{Name: "msg_hash"}}}
+	switch strategy {
+	case ConflictUpdateHashes:
+		onConflict.DoUpdates = clause.Assignments(map[string]interface{}{
+			"layer1_hash": gorm.Expr("CASE WHEN relayed_msg.layer1_hash = '' THEN excluded.layer1_hash ELSE relayed_msg.layer1_hash END"),
+			"layer2_hash": gorm.Expr("CASE WHEN relayed_msg.layer2_hash = '' THEN excluded.layer2_hash ELSE relayed_msg.layer2_hash END"),
+		})
+	case ConflictReplace:
+		onConflict.DoUpdates = clause.AssignmentColumns([]string{"height", "block_hash", "layer1_hash", "layer2_hash"})
+	case ConflictIgnore:
+		fallthrough
+	default:
+		onConflict.DoNothing = true
+	}
+	return onConflict
+}
+
+// chunkRelayedMsgs splits messages into consecutive slices of at most size, preserving order.
+func chunkRelayedMsgs(messages []*RelayedMsg, size int) [][]*RelayedMsg {
+	if size <= 0 || len(messages) == 0 {
+		return nil
+	}
+	chunks := make([][]*RelayedMsg, 0, (len(messages)+size-1)/size)
+	for start := 0; start < len(messages); start += size {
+		end := start + size
+		if end > len(messages) {
+			end = len(messages)
+		}
+		chunks = append(chunks, messages[start:end])
+	}
+	return chunks
+}
+
+// InsertOrUpdateRelayedMsg upserts messages on msg_hash, resolving conflicts per opts.Strategy,
+// in chunks of opts.ChunkSize (default defaultUpsertChunkSize).
+func (r *RelayedMsg) InsertOrUpdateRelayedMsg(ctx context.Context, messages []*RelayedMsg, opts InsertOrUpdateOpts, dbTx ...*gorm.DB) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	db := r.db
+	if len(dbTx) > 0 && dbTx[0] != nil {
+		db = dbTx[0]
+	}
+	db = db.WithContext(ctx)
+
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultUpsertChunkSize
+	}
+
+	onConflict := buildOnConflictClause(opts.Strategy)
+
+	for _, chunk := range chunkRelayedMsgs(messages, chunkSize) {
+		if err := db.Model(&RelayedMsg{}).Clauses(onConflict).Create(&chunk).Error; err != nil {
+			msgHashes := make([]string, 0, len(chunk))
+			for _, msg := range chunk {
+				msgHashes = append(msgHashes, msg.MsgHash)
+			}
+			log.Error("failed to upsert relayed messages", "msgHashes", msgHashes, "err", errors.Field(errors.WithStack(err)))
+			if isSerializationConflictError(err) {
+				return newBatchInsertError(msgHashes, errors.Wrap(ErrTxConflict, "RelayedMsg.InsertOrUpdateRelayedMsg error"))
+			}
+			return newBatchInsertError(msgHashes, errors.Wrap(err, "RelayedMsg.InsertOrUpdateRelayedMsg error"))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package orm
+
+import (
+	"testing"
+)
+
+func TestBuildOnConflictClause(t *testing.T) {
+	tests := []struct {
+		name          string
+		strategy      ConflictStrategy
+		wantDoNothing bool
+		wantColumns   []string // nil means "don't check exact columns"
+	}{
+		{name: "ignore", strategy: ConflictIgnore, wantDoNothing: true},
+		{name: "update hashes", strategy: ConflictUpdateHashes, wantDoNothing: false,
+			wantColumns: []string{"layer1_hash", "layer2_hash"}},
+		{name: "replace", strategy: ConflictReplace, wantDoNothing: false,
+			wantColumns: []string{"height", "block_hash", "layer1_hash", "layer2_hash"}},
+		{name: "unknown strategy defaults to ignore", strategy: ConflictStrategy(99), wantDoNothing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oc := buildOnConflictClause(tt.strategy)
+			if len(oc.Columns) != 1 || oc.Columns[0].Name != "msg_hash" {
+				t.Fatalf("expected conflict target column msg_hash, got %+v", oc.Columns)
+			}
+			if oc.DoNothing != tt.wantDoNothing {
+				t.Fatalf("DoNothing = %v, want %v", oc.DoNothing, tt.wantDoNothing)
+			}
+			if tt.wantColumns != nil {
+				if len(oc.DoUpdates) != len(tt.wantColumns) {
+					t.Fatalf("expected %d assignments, got %d: %+v", len(tt.wantColumns), len(oc.DoUpdates), oc.DoUpdates)
+				}
+				got := make(map[string]bool, len(oc.DoUpdates))
+				for _, assignment := range oc.DoUpdates {
+					got[assignment.Column.Name] = true
+				}
+				for _, col := range tt.wantColumns {
+					if !got[col] {
+						t.Errorf("expected an assignment for column %q, got %+v", col, oc.DoUpdates)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestChunkRelayedMsgs(t *testing.T) {
+	msgs := make([]*RelayedMsg, 7)
+	for i := range msgs {
+		msgs[i] = &RelayedMsg{ID: uint64(i)}
+	}
+
+	chunks := chunkRelayedMsgs(msgs, 3)
+	wantSizes := []int{3, 3, 1}
+	if len(chunks) != len(wantSizes) {
+		t.Fatalf("expected %d chunks, got %d", len(wantSizes), len(chunks))
+	}
+	var seen int
+	for i, chunk := range chunks {
+		if len(chunk) != wantSizes[i] {
+			t.Errorf("chunk %d: expected size %d, got %d", i, wantSizes[i], len(chunk))
+		}
+		for _, msg := range chunk {
+			if msg.ID != uint64(seen) {
+				t.Errorf("chunk %d: expected order-preserving ID %d, got %d", i, seen, msg.ID)
+			}
+			seen++
+		}
+	}
+
+	if got := chunkRelayedMsgs(nil, 3); got != nil {
+		t.Errorf("expected nil for empty input, got %v", got)
+	}
+	if got := chunkRelayedMsgs(msgs, 0); got != nil {
+		t.Errorf("expected nil for non-positive size, got %v", got)
+	}
+}